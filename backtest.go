@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Trade is a closed round-trip position: one buy matched with one sell.
+type Trade struct {
+	Ticker string
+	Open   time.Time
+	Close  time.Time
+	Entry  int
+	Exit   int
+	PnL    int
+}
+
+// Report summarizes a backtest run for a single ticker.
+type Report struct {
+	Ticker        string
+	Trades        []Trade
+	Sharpe        float64
+	MaxDrawdown   int
+	WinRate       float64
+	CumulativePnL int
+}
+
+// bookkeeper accumulates a Report for a single ticker as its orders arrive.
+type bookkeeper struct {
+	report    Report
+	openOrder *Order
+	equity    int
+	peak      int
+}
+
+func (b *bookkeeper) record(o Order) {
+	b.report.Ticker = o.Ticker
+	switch o.Side {
+	case "buy":
+		if b.openOrder == nil {
+			b.openOrder = &o
+		}
+	case "sell":
+		if b.openOrder != nil {
+			trade := Trade{
+				Ticker: o.Ticker,
+				Open:   b.openOrder.Time,
+				Close:  o.Time,
+				Entry:  b.openOrder.Price,
+				Exit:   o.Price,
+				PnL:    o.Price - b.openOrder.Price,
+			}
+			b.report.Trades = append(b.report.Trades, trade)
+			b.openOrder = nil
+
+			b.equity += trade.PnL
+			if b.equity > b.peak {
+				b.peak = b.equity
+			}
+			if dd := b.peak - b.equity; dd > b.report.MaxDrawdown {
+				b.report.MaxDrawdown = dd
+			}
+		}
+	}
+}
+
+func (b *bookkeeper) finish() *Report {
+	b.report.Sharpe = sharpeRatio(b.report.Trades)
+	wins := 0
+	for _, t := range b.report.Trades {
+		b.report.CumulativePnL += t.PnL
+		if t.PnL > 0 {
+			wins++
+		}
+	}
+	if len(b.report.Trades) > 0 {
+		b.report.WinRate = float64(wins) / float64(len(b.report.Trades))
+	}
+	return &b.report
+}
+
+// runBacktest replays a candle stream through a per-ticker Strategy
+// (constructed via newStrategy), matching buy/sell orders into Trades and
+// emitting one Report per ticker once the candle stream closes.
+func runBacktest(newStrategy func() Strategy, in <-chan *Candle, out chan<- *Report) {
+	strategies := make(map[string]Strategy)
+	books := make(map[string]*bookkeeper)
+
+	for candle := range in {
+		strategy, ok := strategies[candle.Ticker]
+		if !ok {
+			strategy = newStrategy()
+			strategies[candle.Ticker] = strategy
+			books[candle.Ticker] = &bookkeeper{report: Report{Ticker: candle.Ticker}}
+		}
+
+		for _, o := range strategy.OnCandle(candle) {
+			books[candle.Ticker].record(o)
+		}
+	}
+
+	for ticker, strategy := range strategies {
+		for _, o := range strategy.OnClose() {
+			books[ticker].record(o)
+		}
+		out <- books[ticker].finish()
+	}
+
+	close(out)
+}
+
+// sharpeRatio computes the (unannualized) Sharpe ratio of per-trade PnL:
+// mean return over its standard deviation. Returns 0 when there are fewer
+// than two trades or the returns have no variance.
+func sharpeRatio(trades []Trade) float64 {
+	if len(trades) < 2 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, t := range trades {
+		sum += float64(t.PnL)
+	}
+	mean := sum / float64(len(trades))
+
+	variance := 0.0
+	for _, t := range trades {
+		d := float64(t.PnL) - mean
+		variance += d * d
+	}
+	variance /= float64(len(trades) - 1)
+	stddev := math.Sqrt(variance)
+	if stddev == 0 {
+		return 0
+	}
+	return mean / stddev
+}
+
+// writeReports writes one report_<ticker>_<span>min.csv file per Report
+// received on in: one line per trade, terminated by a summary row. span
+// is part of the filename so a multi-span backtest (the default 5m/30m/
+// 240m fan-out, or any multi-span -timeframes) doesn't have each span's
+// writer truncate the one before it.
+func writeReports(outputDir string, span time.Duration, errch chan<- error, in <-chan *Report, out chan<- struct{}) {
+	for report := range in {
+		path := filepath.Join(outputDir, fmt.Sprintf("report_%s_%.fmin.csv", report.Ticker, span.Minutes()))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			errch <- err
+			continue
+		}
+
+		for _, t := range report.Trades {
+			if _, err := fmt.Fprintf(f, "trade,%s,%s,%s,%s,%s\n",
+				t.Ticker,
+				t.Open.Format(time.RFC3339),
+				t.Close.Format(time.RFC3339),
+				formatPrice(t.Entry),
+				formatPrice(t.Exit),
+			); err != nil {
+				errch <- err
+			}
+		}
+		if _, err := fmt.Fprintf(f, "summary,%s,%.4f,%s,%.4f,%s\n",
+			report.Ticker,
+			report.Sharpe,
+			formatPrice(report.MaxDrawdown),
+			report.WinRate,
+			formatPrice(report.CumulativePnL),
+		); err != nil {
+			errch <- err
+		}
+
+		f.Close()
+	}
+
+	out <- struct{}{}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSMACrossoverSignals(t *testing.T) {
+	strategy := newSMACrossover(2, 4)
+
+	closes := []int{100, 100, 100, 100, 110, 120, 130, 90, 80, 70}
+	base := time.Date(2019, 01, 30, 07, 0, 0, 0, time.UTC)
+
+	var orders []Order
+	for i, price := range closes {
+		c := &Candle{Ticker: "AAPL", Start: base.Add(time.Duration(i) * time.Minute), PriceEnd: price}
+		orders = append(orders, strategy.OnCandle(c)...)
+	}
+	orders = append(orders, strategy.OnClose()...)
+
+	var buys, sells int
+	for _, o := range orders {
+		switch o.Side {
+		case "buy":
+			buys++
+		case "sell":
+			sells++
+		}
+	}
+
+	if buys == 0 {
+		t.Fatal("expected at least one buy once the fast average crossed above the slow average")
+	}
+	if buys != sells {
+		t.Fatalf("expected every buy to be matched by a sell (including a flattening OnClose); buys=%d sells=%d", buys, sells)
+	}
+}
+
+func TestSMACrossoverHoldsBelowSlowPeriod(t *testing.T) {
+	strategy := newSMACrossover(2, 10)
+	base := time.Date(2019, 01, 30, 07, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		c := &Candle{Ticker: "AAPL", Start: base.Add(time.Duration(i) * time.Minute), PriceEnd: 100 + i*10}
+		if orders := strategy.OnCandle(c); orders != nil {
+			t.Fatalf("expected no orders before slowPeriod candles accumulate; got: %v", orders)
+		}
+	}
+}
+
+func TestBookkeeperTracksDrawdownAndWinRate(t *testing.T) {
+	b := &bookkeeper{}
+	base := time.Date(2019, 01, 30, 07, 0, 0, 0, time.UTC)
+
+	// Two round trips: a loss, then a win that doesn't fully recover the peak.
+	b.record(Order{Ticker: "AAPL", Side: "buy", Price: 100, Time: base})
+	b.record(Order{Ticker: "AAPL", Side: "sell", Price: 90, Time: base.Add(time.Minute)})
+	b.record(Order{Ticker: "AAPL", Side: "buy", Price: 90, Time: base.Add(2 * time.Minute)})
+	b.record(Order{Ticker: "AAPL", Side: "sell", Price: 95, Time: base.Add(3 * time.Minute)})
+
+	report := b.finish()
+
+	if len(report.Trades) != 2 {
+		t.Fatalf("expected 2 trades; got: %d", len(report.Trades))
+	}
+	if report.MaxDrawdown != 10 {
+		t.Fatalf("expected max drawdown of 10; got: %d", report.MaxDrawdown)
+	}
+	if report.WinRate != 0.5 {
+		t.Fatalf("expected a 0.5 win rate; got: %f", report.WinRate)
+	}
+	if report.CumulativePnL != -5 {
+		t.Fatalf("expected cumulative PnL of -5; got: %d", report.CumulativePnL)
+	}
+}
+
+func TestBookkeeperIgnoresUnmatchedSell(t *testing.T) {
+	b := &bookkeeper{}
+	b.record(Order{Ticker: "AAPL", Side: "sell", Price: 100, Time: time.Now()})
+
+	report := b.finish()
+	if len(report.Trades) != 0 {
+		t.Fatalf("expected a sell with no open buy to produce no trade; got: %d", len(report.Trades))
+	}
+}
+
+func TestSharpeRatio(t *testing.T) {
+	if got := sharpeRatio(nil); got != 0 {
+		t.Fatalf("expected 0 sharpe with no trades; got: %f", got)
+	}
+	if got := sharpeRatio([]Trade{{PnL: 10}}); got != 0 {
+		t.Fatalf("expected 0 sharpe with a single trade; got: %f", got)
+	}
+	if got := sharpeRatio([]Trade{{PnL: 10}, {PnL: 10}}); got != 0 {
+		t.Fatalf("expected 0 sharpe with no variance; got: %f", got)
+	}
+
+	got := sharpeRatio([]Trade{{PnL: 10}, {PnL: -10}})
+	if got != 0 {
+		t.Fatalf("expected 0 sharpe for a zero-mean series; got: %f", got)
+	}
+}
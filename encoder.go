@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CandleEncoder serializes a stream of candles for a single (ticker, span)
+// into a format-specific binary or text layout. WriteHeader is called once
+// per file before the first WriteCandle.
+type CandleEncoder interface {
+	// Ext is the file extension used for this format, without the dot.
+	Ext() string
+	WriteHeader(w io.Writer, ticker string, span time.Duration) error
+	WriteCandle(w io.Writer, c *Candle) error
+}
+
+// NewCandleEncoder builds a CandleEncoder by format name. Supported names:
+// "csv" (the default, one shared file per span) and "hst" (MetaTrader 4
+// History version 401, one file per ticker and span).
+func NewCandleEncoder(format string) (CandleEncoder, error) {
+	switch format {
+	case "", "csv":
+		return csvEncoder{}, nil
+	case "hst":
+		return hstEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("pipeline: unknown candle format %q", format)
+	}
+}
+
+// csvEncoder mirrors the layout written by write(): no header, one
+// comma-separated line per candle.
+type csvEncoder struct{}
+
+func (csvEncoder) Ext() string { return "csv" }
+
+func (csvEncoder) WriteHeader(w io.Writer, ticker string, span time.Duration) error { return nil }
+
+func (csvEncoder) WriteCandle(w io.Writer, c *Candle) error {
+	_, err := fmt.Fprintf(w, "%s,%s,%s,%s,%s,%s\n",
+		c.Ticker,
+		c.Start.Format(time.RFC3339),
+		formatPrice(c.PriceStart),
+		formatPrice(c.PriceMax),
+		formatPrice(c.PriceMin),
+		formatPrice(c.PriceEnd),
+	)
+	return err
+}
+
+// hstEncoder writes MetaTrader 4 History files, version 401: a 148-byte
+// file header followed by one 60-byte record per candle.
+type hstEncoder struct{}
+
+func (hstEncoder) Ext() string { return "hst" }
+
+func (hstEncoder) WriteHeader(w io.Writer, ticker string, span time.Duration) error {
+	var header [148]byte
+
+	binary.LittleEndian.PutUint32(header[0:4], 401)
+	copy(header[4:68], []byte("pipeline"))
+	copy(header[68:80], []byte(ticker))
+	binary.LittleEndian.PutUint32(header[80:84], uint32(span.Minutes()))
+	binary.LittleEndian.PutUint32(header[84:88], 2) // digits: fixed-point cents
+	// header[88:92] timesign, header[92:96] last_sync, header[96:148] unused all zero
+
+	_, err := w.Write(header[:])
+	return err
+}
+
+func (hstEncoder) WriteCandle(w io.Writer, c *Candle) error {
+	var record [60]byte
+
+	binary.LittleEndian.PutUint64(record[0:8], uint64(c.Start.Unix()))
+	binary.LittleEndian.PutUint64(record[8:16], math.Float64bits(float64(c.PriceStart)/100.0))
+	binary.LittleEndian.PutUint64(record[16:24], math.Float64bits(float64(c.PriceMax)/100.0))
+	binary.LittleEndian.PutUint64(record[24:32], math.Float64bits(float64(c.PriceMin)/100.0))
+	binary.LittleEndian.PutUint64(record[32:40], math.Float64bits(float64(c.PriceEnd)/100.0))
+	binary.LittleEndian.PutUint64(record[40:48], uint64(c.Volume))
+	// record[48:52] spread=0, record[52:60] real_volume=0
+
+	_, err := w.Write(record[:])
+	return err
+}
+
+// writeEncoded consumes a span's candle stream and writes one file per
+// ticker using enc, opening each file lazily (and writing its header) on
+// the first candle seen for that ticker.
+func writeEncoded(outputDir string, span time.Duration, enc CandleEncoder, errch chan<- error, in <-chan *Candle, out chan<- struct{}) {
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for candle := range in {
+		f, ok := files[candle.Ticker]
+		if !ok {
+			path := filepath.Join(outputDir, fmt.Sprintf("%s_%.fmin.%s", candle.Ticker, span.Minutes(), enc.Ext()))
+			var err error
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				errch <- err
+				continue
+			}
+			if err := enc.WriteHeader(f, candle.Ticker, span); err != nil {
+				errch <- err
+			}
+			files[candle.Ticker] = f
+		}
+
+		if err := enc.WriteCandle(f, candle); err != nil {
+			errch <- err
+		}
+	}
+
+	out <- struct{}{}
+}
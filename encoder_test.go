@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCandleEncoder(t *testing.T) {
+	if enc, err := NewCandleEncoder(""); err != nil || enc.Ext() != "csv" {
+		t.Fatalf("expected the empty format to default to csv; got: %v, %v", enc, err)
+	}
+	if enc, err := NewCandleEncoder("hst"); err != nil || enc.Ext() != "hst" {
+		t.Fatalf("expected hst encoder; got: %v, %v", enc, err)
+	}
+	if _, err := NewCandleEncoder("bogus"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestCSVEncoderWriteCandle(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Candle{
+		Ticker:     "AAPL",
+		Start:      time.Date(2019, 01, 30, 7, 5, 0, 0, time.UTC),
+		PriceStart: 16280,
+		PriceMax:   16300,
+		PriceMin:   16270,
+		PriceEnd:   16290,
+	}
+
+	if err := (csvEncoder{}).WriteCandle(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "AAPL," + c.Start.Format(time.RFC3339) + ",162.8,163,162.7,162.9\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q; got: %q", want, buf.String())
+	}
+}
+
+func TestHSTEncoderHeaderLayout(t *testing.T) {
+	var buf bytes.Buffer
+	enc := hstEncoder{}
+
+	if err := enc.WriteHeader(&buf, "AAPL", 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	header := buf.Bytes()
+	if len(header) != 148 {
+		t.Fatalf("expected a 148-byte header; got: %d bytes", len(header))
+	}
+	if version := binary.LittleEndian.Uint32(header[0:4]); version != 401 {
+		t.Fatalf("expected version 401; got: %d", version)
+	}
+	if copyright := strings.TrimRight(string(header[4:68]), "\x00"); copyright != "pipeline" {
+		t.Fatalf("expected copyright %q; got: %q", "pipeline", copyright)
+	}
+	if symbol := strings.TrimRight(string(header[68:80]), "\x00"); symbol != "AAPL" {
+		t.Fatalf("expected symbol %q; got: %q", "AAPL", symbol)
+	}
+	if period := binary.LittleEndian.Uint32(header[80:84]); period != 5 {
+		t.Fatalf("expected period 5; got: %d", period)
+	}
+	if digits := binary.LittleEndian.Uint32(header[84:88]); digits != 2 {
+		t.Fatalf("expected digits 2; got: %d", digits)
+	}
+}
+
+func TestHSTEncoderRecordLayout(t *testing.T) {
+	var buf bytes.Buffer
+	enc := hstEncoder{}
+
+	c := &Candle{
+		Ticker:     "AAPL",
+		Start:      time.Date(2019, 01, 30, 7, 5, 0, 0, time.UTC),
+		PriceStart: 16280,
+		PriceMax:   16300,
+		PriceMin:   16270,
+		PriceEnd:   16290,
+		Volume:     7,
+	}
+
+	if err := enc.WriteCandle(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+
+	record := buf.Bytes()
+	if len(record) != 60 {
+		t.Fatalf("expected a 60-byte record; got: %d bytes", len(record))
+	}
+	if ts := int64(binary.LittleEndian.Uint64(record[0:8])); ts != c.Start.Unix() {
+		t.Fatalf("expected timestamp %d; got: %d", c.Start.Unix(), ts)
+	}
+
+	fields := []struct {
+		name string
+		off  int
+		want float64
+	}{
+		{"open", 8, 162.80},
+		{"high", 16, 163.00},
+		{"low", 24, 162.70},
+		{"close", 32, 162.90},
+	}
+	for _, f := range fields {
+		got := math.Float64frombits(binary.LittleEndian.Uint64(record[f.off : f.off+8]))
+		if math.Abs(got-f.want) > 1e-9 {
+			t.Fatalf("%s: expected %v; got: %v", f.name, f.want, got)
+		}
+	}
+
+	if vol := binary.LittleEndian.Uint64(record[40:48]); vol != 7 {
+		t.Fatalf("expected volume 7; got: %d", vol)
+	}
+}
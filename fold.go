@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// foldCandles derives span candles from a stream of lower-timeframe
+// candles already ordered by Start: min of mins, max of maxes, first
+// open, last close, and summed volume, bucketed into span using the same
+// session-window rules process() applies to raw ticks.
+func foldCandles(span time.Duration, calendar *SessionCalendar, in <-chan *Candle, out chan<- *Candle) {
+	folds := make(map[string]*Candle)
+
+	c, ok := <-in
+	if !ok {
+		close(out)
+		return
+	}
+
+	l, max, ok := sessionWindow(calendar, c.Start)
+	if !ok {
+		l, max, ok = nextSessionWindow(calendar, c.Start)
+		if !ok {
+			close(out)
+			return
+		}
+	}
+	r := l.Add(span)
+	if r.After(max) {
+		r = max
+	}
+
+	foldInto(folds, l, c)
+
+	for c := range in {
+		if c.Start.Before(l) {
+			continue // discard: before session open
+		}
+
+		if c.Start.After(r) || c.Start.Equal(r) {
+			for k, f := range folds {
+				out <- f
+				delete(folds, k)
+			}
+
+			for c.Start.After(r) || c.Start.Equal(r) {
+				if r.Equal(max) {
+					next, nmax, ok := nextSessionWindow(calendar, max)
+					if !ok {
+						close(out)
+						return
+					}
+					l, r, max = next, next.Add(span), nmax
+				} else {
+					l = l.Add(span)
+					r = r.Add(span)
+				}
+				if r.After(max) {
+					r = max
+				}
+			}
+		}
+
+		if c.Start.Before(l) {
+			continue // discard: outside session (weekend/holiday)
+		}
+
+		foldInto(folds, l, c)
+	}
+
+	for _, f := range folds {
+		out <- f
+	}
+	close(out)
+}
+
+func foldInto(folds map[string]*Candle, bucketStart time.Time, c *Candle) {
+	f, ok := folds[c.Ticker]
+	if !ok {
+		folds[c.Ticker] = &Candle{
+			Ticker:     c.Ticker,
+			Start:      bucketStart,
+			PriceStart: c.PriceStart,
+			PriceEnd:   c.PriceEnd,
+			PriceMin:   c.PriceMin,
+			PriceMax:   c.PriceMax,
+			Volume:     c.Volume,
+		}
+		return
+	}
+
+	if c.PriceMin < f.PriceMin {
+		f.PriceMin = c.PriceMin
+	}
+	if c.PriceMax > f.PriceMax {
+		f.PriceMax = c.PriceMax
+	}
+	f.PriceEnd = c.PriceEnd
+	f.Volume += c.Volume
+}
+
+// parseTimeframes parses a comma-separated list of timeframe specs such
+// as "1m,5m,15m,1h,4h,1d" into sorted, de-duplicated durations.
+func parseTimeframes(spec string) ([]time.Duration, error) {
+	var spans []time.Duration
+	seen := make(map[time.Duration]bool)
+
+	start := 0
+	for i := 0; i <= len(spec); i++ {
+		if i < len(spec) && spec[i] != ',' {
+			continue
+		}
+		tok := spec[start:i]
+		start = i + 1
+		if tok == "" {
+			continue
+		}
+
+		span, err := parseTimeframe(tok)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[span] {
+			seen[span] = true
+			spans = append(spans, span)
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i] < spans[j] })
+	return spans, nil
+}
+
+func parseTimeframe(tok string) (time.Duration, error) {
+	if len(tok) < 2 {
+		return 0, fmt.Errorf("pipeline: invalid timeframe %q", tok)
+	}
+
+	n, err := strconv.Atoi(tok[:len(tok)-1])
+	if err != nil {
+		return 0, fmt.Errorf("pipeline: invalid timeframe %q", tok)
+	}
+
+	switch tok[len(tok)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("pipeline: invalid timeframe %q", tok)
+	}
+}
+
+// cascade builds raw ticks -> sharded 1-minute candles -> progressively
+// folded higher timeframes, returning one candle stream per span in
+// spans (which must be sorted ascending). Timeframes other than the
+// 1-minute base are each folded from the previous tier in spans, so a
+// span must be derivable from the immediately preceding span (or from
+// the 1-minute base, if it is first).
+//
+// foldCandles requires its input ordered by Start, which only holds
+// within a single shard's own candle stream (one process goroutine,
+// emitting in non-decreasing time order). So every span's fold chain
+// runs per shard, entirely before crossing shard boundaries; only the
+// final, already-folded per-span streams are merged across shards.
+func cascade(spans []time.Duration, calendar *SessionCalendar, workers int, in <-chan *Line) map[time.Duration]<-chan *Candle {
+	const base = time.Minute
+
+	shards := shardLines(workers, in)
+
+	shardOuts := make(map[time.Duration][]<-chan *Candle, len(spans))
+	for _, span := range spans {
+		shardOuts[span] = make([]<-chan *Candle, len(shards))
+	}
+
+	for i, shard := range shards {
+		wout := make(chan *Candle)
+		go process(base, calendar, shard, wout)
+
+		var cur <-chan *Candle = wout
+		for j, span := range spans {
+			tier := cur
+			if span != base {
+				folded := make(chan *Candle)
+				go foldCandles(span, calendar, cur, folded)
+				tier = folded
+			}
+
+			if j < len(spans)-1 {
+				toNext := make(chan *Candle)
+				toOut := make(chan *Candle)
+				go teeCandles(tier, toNext, toOut)
+				shardOuts[span][i] = toOut
+				cur = toNext
+			} else {
+				shardOuts[span][i] = tier
+			}
+		}
+	}
+
+	outs := make(map[time.Duration]<-chan *Candle, len(spans))
+	for _, span := range spans {
+		outs[span] = mergeCandles(shardOuts[span]...)
+	}
+
+	return outs
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 	"time"
 )
 
@@ -12,8 +15,20 @@ type Options struct {
 	InputFilePath string
 	OutputDir     string
 	Timeout       int
+
+	Backtest   bool
+	Strategy   string
+	From       string
+	To         string
+	Format     string
+	Session    string
+	Source     string
+	Workers    int
+	Timeframes string
 }
 
+const dateLayout = "2006-01-02"
+
 func main() {
 	opts := Options{
 		OutputDir: ".",
@@ -21,15 +36,31 @@ func main() {
 	}
 
 	flag.StringVar(&opts.InputFilePath, "file", "", "input file path")
+	flag.BoolVar(&opts.Backtest, "backtest", false, "run a strategy backtest alongside candle aggregation")
+	flag.StringVar(&opts.Strategy, "strategy", "sma", "backtest strategy to run (sma)")
+	flag.StringVar(&opts.From, "from", "", "clip the input stream to lines on or after this date (YYYY-MM-DD)")
+	flag.StringVar(&opts.To, "to", "", "clip the input stream to lines before this date (YYYY-MM-DD)")
+	flag.StringVar(&opts.Format, "format", "csv", "candle output format (csv, hst)")
+	flag.StringVar(&opts.Session, "session", "", "trading session calendar: a preset (moex, nyse, crypto) or a path to a YAML or JSON SessionCalendar file")
+	flag.StringVar(&opts.Source, "source", "", "live tick source (tcp://host:port or ws://host:port/path) to stream from instead of -file")
+	flag.IntVar(&opts.Workers, "workers", runtime.GOMAXPROCS(0), "number of per-ticker aggregation workers per timeframe")
+	flag.StringVar(&opts.Timeframes, "timeframes", "", "comma-separated cascade of timeframes to derive by folding, e.g. 1m,5m,15m,1h,4h,1d (default: the fixed 5m/30m/4h set)")
 
 	flag.Parse()
 
-	if opts.InputFilePath == "" {
+	if opts.InputFilePath == "" && opts.Source == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Duration(opts.Timeout)*time.Millisecond))
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if opts.Source != "" {
+		// Streaming mode runs until the process is asked to stop.
+		ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	} else {
+		ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(time.Duration(opts.Timeout)*time.Millisecond))
+	}
 	defer cancel()
 
 	if err := pipeline(ctx, &opts); err != nil {
@@ -31,9 +31,10 @@ type Candle struct {
 	PriceEnd   int
 	PriceMin   int
 	PriceMax   int
+	Volume     int
 }
 
-func newCandle(ts time.Time, ticker string, price int) *Candle {
+func newCandle(ts time.Time, ticker string, price, count int) *Candle {
 	return &Candle{
 		Ticker:     ticker,
 		Start:      ts,
@@ -41,46 +42,115 @@ func newCandle(ts time.Time, ticker string, price int) *Candle {
 		PriceMax:   price,
 		PriceMin:   price,
 		PriceEnd:   price,
+		Volume:     count,
 	}
 }
 
 func pipeline(ctx context.Context, opts *Options) error {
-	in, err := os.Open(opts.InputFilePath)
+	var source LineSource
+	if opts.Source != "" {
+		src, err := NewLineSource(opts.Source)
+		if err != nil {
+			return err
+		}
+		source = src
+	} else {
+		in, err := os.Open(opts.InputFilePath)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		source = fileSource{r: in}
+	}
+
+	var from, to time.Time
+	var err error
+	if opts.From != "" {
+		if from, err = time.Parse(dateLayout, opts.From); err != nil {
+			return err
+		}
+	}
+	if opts.To != "" {
+		if to, err = time.Parse(dateLayout, opts.To); err != nil {
+			return err
+		}
+	}
+
+	if opts.Backtest {
+		if _, err := NewStrategy(opts.Strategy); err != nil {
+			return err
+		}
+	}
+
+	encoder, err := NewCandleEncoder(opts.Format)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
+
+	var calendar *SessionCalendar
+	if opts.Session != "" {
+		if calendar, err = LoadSessionCalendar(opts.Session); err != nil {
+			return err
+		}
+	}
 
 	errch := make(chan error)
 	done := make(chan struct{})
+	pending := 0
 
-	tasks := []struct {
-		span time.Duration
-		in   chan *Line
-		out  chan *Candle
-	}{
-		{span: 5 * time.Minute, in: make(chan *Line), out: make(chan *Candle)},
-		{span: 30 * time.Minute, in: make(chan *Line), out: make(chan *Candle)},
-		{span: 240 * time.Minute, in: make(chan *Line), out: make(chan *Candle)},
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
 	}
 
-	ins := make([]chan<- *Line, 0, len(tasks))
-	for _, task := range tasks {
-		ins = append(ins, task.in)
+	var ins []chan<- *Line
 
-		path := filepath.Join(opts.OutputDir, fmt.Sprintf("candles_%.fmin.csv", task.span.Minutes()))
-		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if opts.Timeframes != "" {
+		spans, err := parseTimeframes(opts.Timeframes)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
 
-		go process(task.span, task.in, task.out)
-		go write(f, errch, task.out, done)
+		raw := make(chan *Line)
+		ins = append(ins, raw)
+		clipped := make(chan *Line)
+		go clip(from, to, raw, clipped)
+
+		for span, candles := range cascade(spans, calendar, workers, clipped) {
+			n, err := sinkCandles(opts, encoder, span, candles, errch, done)
+			if err != nil {
+				return err
+			}
+			pending += n
+		}
+	} else {
+		tasks := []time.Duration{5 * time.Minute, 30 * time.Minute, 240 * time.Minute}
+
+		for _, span := range tasks {
+			raw := make(chan *Line)
+			ins = append(ins, raw)
+			clipped := make(chan *Line)
+			go clip(from, to, raw, clipped)
+
+			shards := shardLines(workers, clipped)
+			workerOuts := make([]<-chan *Candle, workers)
+			for i, shard := range shards {
+				wout := make(chan *Candle)
+				go process(span, calendar, shard, wout)
+				workerOuts[i] = wout
+			}
+			candles := mergeCandles(workerOuts...)
+
+			n, err := sinkCandles(opts, encoder, span, candles, errch, done)
+			if err != nil {
+				return err
+			}
+			pending += n
+		}
 	}
-	go read(in, errch, ins...)
+	go source.Run(ctx, errch, ins...)
 
-	for i := 0; i < len(tasks); i++ {
+	for i := 0; i < pending; i++ {
 		select {
 		case err := <-errch:
 			return err
@@ -93,6 +163,88 @@ func pipeline(ctx context.Context, opts *Options) error {
 	return nil
 }
 
+// sinkCandles wires a single span's candle stream into whatever output
+// stages opts enable: an optional backtest pass, then the configured
+// candle writer. It returns how many of those stages signal completion
+// on done, for the caller's pending-count bookkeeping.
+func sinkCandles(opts *Options, encoder CandleEncoder, span time.Duration, candles <-chan *Candle, errch chan<- error, done chan<- struct{}) (int, error) {
+	pending := 0
+
+	var toWrite <-chan *Candle = candles
+	if opts.Backtest {
+		w := make(chan *Candle)
+		toBacktest := make(chan *Candle)
+		go teeCandles(candles, w, toBacktest)
+		toWrite = w
+
+		reports := make(chan *Report)
+		go runBacktest(func() Strategy {
+			strategy, _ := NewStrategy(opts.Strategy)
+			return strategy
+		}, toBacktest, reports)
+		go writeReports(opts.OutputDir, span, errch, reports, done)
+		pending++
+	}
+
+	isCSV := opts.Format == "" || opts.Format == "csv"
+
+	switch {
+	case isCSV && opts.Source != "":
+		// Streaming mode: roll over to a new file each UTC day.
+		rf := newRotatingFile(func(day time.Time) string {
+			name := fmt.Sprintf("candles_%.fmin_%s.csv", span.Minutes(), day.Format("2006-01-02"))
+			return filepath.Join(opts.OutputDir, name)
+		})
+		go func() {
+			write(rf, errch, toWrite, done)
+			rf.Close()
+		}()
+	case isCSV:
+		path := filepath.Join(opts.OutputDir, fmt.Sprintf("candles_%.fmin.csv", span.Minutes()))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return 0, err
+		}
+		go func() {
+			write(f, errch, toWrite, done)
+			f.Close()
+		}()
+	default:
+		go writeEncoded(opts.OutputDir, span, encoder, errch, toWrite, done)
+	}
+	pending++
+
+	return pending, nil
+}
+
+// clip forwards lines from in to out, dropping any line before from (when
+// from is non-zero) or on/after to (when to is non-zero).
+func clip(from, to time.Time, in <-chan *Line, out chan<- *Line) {
+	for line := range in {
+		if !from.IsZero() && line.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !line.Timestamp.Before(to) {
+			continue
+		}
+		out <- line
+	}
+	close(out)
+}
+
+// teeCandles forwards every candle from in to each of outs, closing all
+// outs once in closes.
+func teeCandles(in <-chan *Candle, outs ...chan<- *Candle) {
+	for candle := range in {
+		for _, out := range outs {
+			out <- candle
+		}
+	}
+	for _, out := range outs {
+		close(out)
+	}
+}
+
 func read(r io.Reader, errch chan<- error, outs ...chan<- *Line) {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
@@ -120,7 +272,34 @@ const (
 	TradesDuration = 1020 * time.Minute // 24:00 UTC
 )
 
-func process(span time.Duration, in <-chan *Line, out chan<- *Candle) {
+// sessionWindow returns the trading window covering t. With a nil
+// calendar it falls back to the historical fixed 07:00-24:00 UTC session.
+func sessionWindow(calendar *SessionCalendar, t time.Time) (start, end time.Time, ok bool) {
+	if calendar == nil {
+		y, m, d := t.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		start = day.Add(TradesStart)
+		return start, start.Add(TradesDuration), true
+	}
+	return calendar.window(t)
+}
+
+// nextSessionWindow returns the trading window that starts at or after
+// the end of the current one (end). With a nil calendar this is simply
+// the same fixed session on the following UTC day.
+func nextSessionWindow(calendar *SessionCalendar, end time.Time) (start, nend time.Time, ok bool) {
+	if calendar == nil {
+		y, m, d := end.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		start = day.Add(TradesStart)
+		return start, start.Add(TradesDuration), true
+	}
+	// A "24:00" close puts end at midnight, already on the next calendar
+	// day; step back so nextWindow does not skip that day's window.
+	return calendar.nextWindow(end.Add(-time.Nanosecond))
+}
+
+func process(span time.Duration, calendar *SessionCalendar, in <-chan *Line, out chan<- *Candle) {
 	candles := make(map[string]*Candle)
 
 	line, ok := <-in
@@ -129,25 +308,33 @@ func process(span time.Duration, in <-chan *Line, out chan<- *Candle) {
 		return
 	}
 
-	y, m, d := line.Timestamp.Date()
-	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
-	start := day.Add(TradesStart)
+	start, max, ok := sessionWindow(calendar, line.Timestamp)
+	if !ok {
+		start, max, ok = nextSessionWindow(calendar, line.Timestamp)
+		if !ok {
+			close(out)
+			return
+		}
+	}
 
 	interval := struct {
 		l, r, max time.Time
 	}{
 		l:   start,
 		r:   start.Add(span),
-		max: start.Add(TradesDuration),
+		max: max,
+	}
+	if interval.r.After(interval.max) {
+		interval.r = interval.max
 	}
 
 	if !line.Timestamp.Before(interval.l) {
-		candles[line.Ticker] = newCandle(interval.l, line.Ticker, line.Price)
+		candles[line.Ticker] = newCandle(interval.l, line.Ticker, line.Price, line.Count)
 	}
 
 	for line := range in {
 		if line.Timestamp.Before(interval.l) {
-			continue // discard line
+			continue // discard line: before session open
 		}
 
 		if line.Timestamp.After(interval.r) || line.Timestamp.Equal(interval.r) {
@@ -157,25 +344,33 @@ func process(span time.Duration, in <-chan *Line, out chan<- *Candle) {
 				delete(candles, k)
 			}
 
-			// adjust interval
-			for line.Timestamp.After(interval.r) {
+			// adjust interval, skipping closed days and holidays
+			for line.Timestamp.After(interval.r) || line.Timestamp.Equal(interval.r) {
 				if interval.r.Equal(interval.max) {
-					day = day.Add(24 * time.Hour)
-					interval.l = day.Add(TradesStart)
-					interval.r = interval.l.Add(span)
-					interval.max = interval.l.Add(TradesDuration)
+					next, nmax, ok := nextSessionWindow(calendar, interval.max)
+					if !ok {
+						close(out)
+						return
+					}
+					interval.l = next
+					interval.r = next.Add(span)
+					interval.max = nmax
 				} else {
 					interval.l = interval.l.Add(span)
 					interval.r = interval.r.Add(span)
-					if interval.r.After(interval.max) {
-						interval.r = interval.max
-					}
+				}
+				if interval.r.After(interval.max) {
+					interval.r = interval.max
 				}
 			}
 
 			// proceed with aggregation
 		}
 
+		if line.Timestamp.Before(interval.l) {
+			continue // discard line: outside session (weekend/holiday)
+		}
+
 		// aggregate lines by ticker
 		if candle, ok := candles[line.Ticker]; ok {
 			if line.Price < candle.PriceMin {
@@ -185,8 +380,9 @@ func process(span time.Duration, in <-chan *Line, out chan<- *Candle) {
 				candle.PriceMax = line.Price
 			}
 			candle.PriceEnd = line.Price
+			candle.Volume += line.Count
 		} else {
-			candles[line.Ticker] = newCandle(interval.l, line.Ticker, line.Price)
+			candles[line.Ticker] = newCandle(interval.l, line.Ticker, line.Price, line.Count)
 		}
 	}
 
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// rotatingFile is an io.Writer that reopens its underlying file whenever
+// the UTC calendar day changes, so a long-running streaming pipeline
+// doesn't write an unbounded single file. pathFor derives the file path
+// for a given day.
+type rotatingFile struct {
+	pathFor func(time.Time) string
+	day     string
+	f       *os.File
+}
+
+func newRotatingFile(pathFor func(time.Time) string) *rotatingFile {
+	return &rotatingFile{pathFor: pathFor}
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+
+	if day != r.day || r.f == nil {
+		f, err := os.OpenFile(r.pathFor(now), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return 0, err
+		}
+		if r.f != nil {
+			r.f.Close()
+		}
+		r.f = f
+		r.day = day
+	}
+
+	return r.f.Write(p)
+}
+
+func (r *rotatingFile) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileWritesToDerivedPath(t *testing.T) {
+	dir := t.TempDir()
+
+	var gotDay time.Time
+	rf := newRotatingFile(func(day time.Time) string {
+		gotDay = day
+		return filepath.Join(dir, day.Format("2006-01-02")+".csv")
+	})
+	defer rf.Close()
+
+	if _, err := rf.Write([]byte("AAPL,213.82\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, gotDay.Format("2006-01-02")+".csv")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "AAPL,213.82\n" {
+		t.Fatalf("expected the write to land in the day's file; got: %q", data)
+	}
+}
+
+func TestRotatingFileAppendsWithinSameDay(t *testing.T) {
+	dir := t.TempDir()
+	rf := newRotatingFile(func(day time.Time) string {
+		return filepath.Join(dir, "candles.csv")
+	})
+	defer rf.Close()
+
+	rf.Write([]byte("first\n"))
+	rf.Write([]byte("second\n"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "candles.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Fatalf("expected both writes to land in one file without truncation; got: %q", data)
+	}
+}
+
+func TestRotatingFileCloseIsSafeWithoutWrite(t *testing.T) {
+	rf := newRotatingFile(func(day time.Time) string { return filepath.Join(t.TempDir(), "unused.csv") })
+	if err := rf.Close(); err != nil {
+		t.Fatalf("expected Close on a never-written rotatingFile to be a no-op; got: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Window is a single day's trading session, expressed as "15:04"
+// wall-clock times in the calendar's Location. Close may be "24:00" to
+// mean midnight at the end of the day.
+type Window struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// SessionCalendar describes when a market is open: a timezone, a trading
+// window per weekday, and a set of full-day holidays. Lines timestamped
+// outside the resulting window are filtered out of the candle stream.
+type SessionCalendar struct {
+	Location string            `json:"location"`
+	Windows  map[string]Window `json:"windows"`
+	Holidays []string          `json:"holidays"`
+
+	loc *time.Location
+}
+
+// LoadSessionCalendar resolves path to a SessionCalendar. The built-in
+// preset names "moex", "nyse" and "crypto" are recognized directly;
+// anything else is read as a YAML (".yaml"/".yml") or JSON file in the
+// SessionCalendar shape.
+func LoadSessionCalendar(path string) (*SessionCalendar, error) {
+	switch path {
+	case "moex":
+		return moexCalendar()
+	case "nyse":
+		return nyseCalendar()
+	case "crypto":
+		return cryptoCalendar()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := new(SessionCalendar)
+	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		if err := parseSessionCalendarYAML(data, cal); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, cal); err != nil {
+		return nil, err
+	}
+	if err := cal.init(); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// parseSessionCalendarYAML parses the small YAML subset a session
+// calendar file needs: a flat "location" key, a "windows" mapping of
+// weekday -> {open, close}, and a "holidays" list. Good enough for this
+// one config shape without pulling in a YAML library.
+//
+//	location: Europe/Moscow
+//	windows:
+//	  Mon:
+//	    open: "10:00"
+//	    close: "18:40"
+//	holidays:
+//	  - 2019-01-01
+func parseSessionCalendarYAML(data []byte, cal *SessionCalendar) error {
+	cal.Windows = make(map[string]Window)
+
+	var section, day string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case indent == 0:
+			key, val, _ := strings.Cut(trimmed, ":")
+			key, val = strings.TrimSpace(key), yamlUnquote(strings.TrimSpace(val))
+			section = key
+			switch key {
+			case "location":
+				cal.Location = val
+			case "windows", "holidays":
+				// nested lines follow
+			default:
+				return fmt.Errorf("session: unknown key %q", key)
+			}
+		case section == "windows" && indent == 2:
+			key, _, _ := strings.Cut(trimmed, ":")
+			day = strings.TrimSpace(key)
+			cal.Windows[day] = Window{}
+		case section == "windows" && indent == 4:
+			key, val, _ := strings.Cut(trimmed, ":")
+			key, val = strings.TrimSpace(key), yamlUnquote(strings.TrimSpace(val))
+			w := cal.Windows[day]
+			switch key {
+			case "open":
+				w.Open = val
+			case "close":
+				w.Close = val
+			default:
+				return fmt.Errorf("session: unknown window key %q", key)
+			}
+			cal.Windows[day] = w
+		case section == "holidays" && strings.HasPrefix(trimmed, "-"):
+			cal.Holidays = append(cal.Holidays, yamlUnquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+		default:
+			return fmt.Errorf("session: unexpected line %q", line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func yamlUnquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func (c *SessionCalendar) init() error {
+	loc, err := time.LoadLocation(c.Location)
+	if err != nil {
+		return err
+	}
+	c.loc = loc
+	return nil
+}
+
+func moexCalendar() (*SessionCalendar, error) {
+	cal := &SessionCalendar{
+		Location: "Europe/Moscow",
+		Windows:  uniformWindow(Window{Open: "10:00", Close: "18:40"}, "Mon", "Tue", "Wed", "Thu", "Fri"),
+	}
+	return cal, cal.init()
+}
+
+func nyseCalendar() (*SessionCalendar, error) {
+	cal := &SessionCalendar{
+		Location: "America/New_York",
+		Windows:  uniformWindow(Window{Open: "09:30", Close: "16:00"}, "Mon", "Tue", "Wed", "Thu", "Fri"),
+	}
+	return cal, cal.init()
+}
+
+func cryptoCalendar() (*SessionCalendar, error) {
+	cal := &SessionCalendar{
+		Location: "UTC",
+		Windows:  uniformWindow(Window{Open: "00:00", Close: "24:00"}, "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"),
+	}
+	return cal, cal.init()
+}
+
+func uniformWindow(w Window, weekdays ...string) map[string]Window {
+	windows := make(map[string]Window, len(weekdays))
+	for _, d := range weekdays {
+		windows[d] = w
+	}
+	return windows
+}
+
+// window returns the trading window covering the day of t in the
+// calendar's location, and false if that day has no session (a weekday
+// absent from Windows, or a listed holiday).
+func (c *SessionCalendar) window(t time.Time) (start, end time.Time, ok bool) {
+	local := t.In(c.loc)
+
+	date := local.Format("2006-01-02")
+	for _, h := range c.Holidays {
+		if h == date {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	w, ok := c.Windows[local.Weekday().String()[:3]]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	y, m, d := local.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+
+	start, err := parseWallClock(day, w.Open)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	if w.Close == "24:00" {
+		end = day.AddDate(0, 0, 1)
+	} else if end, err = parseWallClock(day, w.Close); err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return start, end, true
+}
+
+// nextWindow returns the first trading window that starts on a calendar
+// day strictly after t, searching forward day by day (capped at two
+// weeks to tolerate calendars with no open days, e.g. a misconfigured
+// holiday list).
+func (c *SessionCalendar) nextWindow(t time.Time) (start, end time.Time, ok bool) {
+	local := t.In(c.loc)
+	y, m, d := local.Date()
+	day := time.Date(y, m, d+1, 0, 0, 0, 0, c.loc)
+
+	for i := 0; i < 14; i++ {
+		if start, end, ok = c.window(day); ok {
+			return start, end, true
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func parseWallClock(day time.Time, hhmm string) (time.Time, error) {
+	var hh, mm int
+	if _, err := fmt.Sscanf(hhmm, "%d:%d", &hh, &mm); err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := day.Date()
+	return time.Date(y, m, d, hh, mm, 0, 0, day.Location()), nil
+}
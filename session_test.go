@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMoexCalendarWindow(t *testing.T) {
+	cal, err := moexCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tuesday 2019-01-29, well inside the 10:00-18:40 Moscow session.
+	mid := time.Date(2019, 01, 29, 12, 0, 0, 0, time.UTC)
+	start, end, ok := cal.window(mid)
+	if !ok {
+		t.Fatal("expected a session on a weekday")
+	}
+	if h, m := start.In(cal.loc).Hour(), start.In(cal.loc).Minute(); h != 10 || m != 0 {
+		t.Fatalf("expected session open at 10:00 local; got: %02d:%02d", h, m)
+	}
+	if h, m := end.In(cal.loc).Hour(), end.In(cal.loc).Minute(); h != 18 || m != 40 {
+		t.Fatalf("expected session close at 18:40 local; got: %02d:%02d", h, m)
+	}
+
+	// Saturday: no session.
+	weekend := time.Date(2019, 02, 02, 12, 0, 0, 0, time.UTC)
+	if _, _, ok := cal.window(weekend); ok {
+		t.Fatal("expected no session on a weekend")
+	}
+}
+
+func TestSessionCalendarHolidayFiltered(t *testing.T) {
+	cal, err := moexCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal.Holidays = []string{"2019-01-29"}
+
+	holiday := time.Date(2019, 01, 29, 12, 0, 0, 0, time.UTC)
+	if _, _, ok := cal.window(holiday); ok {
+		t.Fatal("expected a listed holiday to have no session, even on an otherwise open weekday")
+	}
+}
+
+func TestSessionCalendarNextWindowSkipsWeekend(t *testing.T) {
+	cal, err := moexCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday close -> next session should be the following Monday, not Saturday.
+	fridayClose := time.Date(2019, 02, 01, 18, 40, 0, 0, cal.loc)
+	start, _, ok := cal.nextWindow(fridayClose)
+	if !ok {
+		t.Fatal("expected a next session to be found")
+	}
+	if wd := start.In(cal.loc).Weekday(); wd != time.Monday {
+		t.Fatalf("expected the next session to land on Monday; got: %s", wd)
+	}
+}
+
+func TestCryptoCalendarHas24x7Session(t *testing.T) {
+	cal, err := cryptoCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sat := time.Date(2019, 02, 02, 3, 0, 0, 0, time.UTC)
+	start, end, ok := cal.window(sat)
+	if !ok {
+		t.Fatal("expected crypto calendar to have a session every day including weekends")
+	}
+	if end.Sub(start) != 24*time.Hour {
+		t.Fatalf("expected a full 24h session; got: %s", end.Sub(start))
+	}
+}
+
+func TestLoadSessionCalendarYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	contents := `location: Europe/Moscow
+windows:
+  Mon:
+    open: "10:00"
+    close: "18:40"
+  Tue:
+    open: "10:00"
+    close: "18:40"
+holidays:
+  - 2019-01-01
+`
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cal, err := LoadSessionCalendar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cal.Location != "Europe/Moscow" {
+		t.Fatalf("expected location Europe/Moscow; got: %q", cal.Location)
+	}
+	if w := cal.Windows["Mon"]; w.Open != "10:00" || w.Close != "18:40" {
+		t.Fatalf("expected Mon window 10:00-18:40; got: %+v", w)
+	}
+	if len(cal.Holidays) != 1 || cal.Holidays[0] != "2019-01-01" {
+		t.Fatalf("expected a single 2019-01-01 holiday; got: %v", cal.Holidays)
+	}
+
+	mon := time.Date(2019, 01, 28, 12, 0, 0, 0, time.UTC)
+	if _, _, ok := cal.window(mon); !ok {
+		t.Fatal("expected a session on the configured Monday window")
+	}
+}
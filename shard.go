@@ -0,0 +1,58 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// shardLines partitions in into n channels by hashing each line's ticker,
+// so every ticker always lands on the same shard and keeps its original
+// relative order within that shard. All n channels are closed once in
+// closes.
+func shardLines(n int, in <-chan *Line) []chan *Line {
+	shards := make([]chan *Line, n)
+	for i := range shards {
+		shards[i] = make(chan *Line)
+	}
+
+	go func() {
+		for line := range in {
+			shards[hashTicker(line.Ticker)%uint32(n)] <- line
+		}
+		for _, shard := range shards {
+			close(shard)
+		}
+	}()
+
+	return shards
+}
+
+func hashTicker(ticker string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(ticker))
+	return h.Sum32()
+}
+
+// mergeCandles fans multiple candle producers into a single channel,
+// closing it once every input has closed.
+func mergeCandles(ins ...<-chan *Candle) <-chan *Candle {
+	out := make(chan *Candle)
+
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan *Candle) {
+			defer wg.Done()
+			for c := range in {
+				out <- c
+			}
+		}(in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// LineSource produces a stream of *Line values onto one or more output
+// channels, the same contract read() has always had, but long-lived: a
+// LineSource may run until ctx is canceled rather than until a single
+// file is exhausted.
+type LineSource interface {
+	Run(ctx context.Context, errch chan<- error, outs ...chan<- *Line)
+}
+
+// fileSource replays a single io.Reader once, matching the historical
+// batch behavior of read().
+type fileSource struct {
+	r io.Reader
+}
+
+func (s fileSource) Run(ctx context.Context, errch chan<- error, outs ...chan<- *Line) {
+	read(s.r, errch, outs...)
+}
+
+// NewLineSource resolves a -source value into a LineSource. An empty spec
+// yields a nil LineSource and no error; a plain path opens that path as a
+// file. "tcp://host:port" and "ws://host:port/path" connect to a live tick
+// server.
+func NewLineSource(spec string) (LineSource, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case hasScheme(spec, "tcp://"):
+		return &tcpSource{addr: spec[len("tcp://"):], bufSize: 4096}, nil
+	case hasScheme(spec, "ws://"), hasScheme(spec, "wss://"):
+		return &wsSource{addr: spec, bufSize: 4096}, nil
+	default:
+		f, err := os.Open(spec)
+		if err != nil {
+			return nil, err
+		}
+		return fileSource{r: f}, nil
+	}
+}
+
+func hasScheme(spec, scheme string) bool {
+	return len(spec) >= len(scheme) && spec[:len(scheme)] == scheme
+}
+
+// tickMessage is the JSON tick shape accepted by network sources,
+// alongside the plain CSV line format parse() already understands.
+type tickMessage struct {
+	Ticker    string `json:"ticker"`
+	Price     string `json:"price"`
+	Count     int    `json:"count"`
+	Timestamp string `json:"timestamp"`
+}
+
+// parseTick decodes a single tick message: JSON if it looks like an
+// object, otherwise the CSV format parse() uses for batch files. Blank
+// lines (e.g. keep-alives) decode to a nil *Line, nil error.
+func parseTick(raw string) (*Line, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if raw[0] != '{' {
+		return parse(raw)
+	}
+
+	var msg tickMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return nil, err
+	}
+
+	price, err := parsePrice(msg.Price)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := time.Parse("2006-01-02 15:04:05.999999", msg.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Line{Ticker: msg.Ticker, Price: price, Count: msg.Count, Timestamp: ts}, nil
+}
+
+// sleepCtx waits for d or ctx cancellation, whichever comes first,
+// reporting which one happened.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+const maxBackoff = 30 * time.Second
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func closeLines(outs []chan<- *Line) {
+	for _, out := range outs {
+		close(out)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTickCSV(t *testing.T) {
+	line, err := parseTick("AAPL,213.82,5,2019-01-30 07:00:09.000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line.Ticker != "AAPL" || line.Price != 21382 || line.Count != 5 {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+}
+
+func TestParseTickJSON(t *testing.T) {
+	line, err := parseTick(`{"ticker":"AAPL","price":"213.82","count":5,"timestamp":"2019-01-30 07:00:09.000000"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line.Ticker != "AAPL" || line.Price != 21382 || line.Count != 5 {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+}
+
+func TestParseTickBlankIsKeepAlive(t *testing.T) {
+	line, err := parseTick("")
+	if err != nil || line != nil {
+		t.Fatalf("expected a blank tick to decode to (nil, nil); got: (%v, %v)", line, err)
+	}
+}
+
+func TestNextBackoffCapsAtMax(t *testing.T) {
+	d := time.Second
+	for i := 0; i < 10; i++ {
+		d = nextBackoff(d)
+	}
+	if d != maxBackoff {
+		t.Fatalf("expected backoff to cap at %s; got: %s", maxBackoff, d)
+	}
+}
+
+func TestSleepCtxReturnsFalseOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if ok := sleepCtx(ctx, time.Minute); ok {
+		t.Fatal("expected sleepCtx to report cancellation, not a completed sleep")
+	}
+}
+
+func TestHasScheme(t *testing.T) {
+	if !hasScheme("tcp://host:1234", "tcp://") {
+		t.Fatal("expected tcp:// spec to match the tcp:// scheme")
+	}
+	if hasScheme("host:1234", "tcp://") {
+		t.Fatal("expected a bare host:port to not match the tcp:// scheme")
+	}
+}
+
+func TestWsConnReadDecodesMaskedTextFrame(t *testing.T) {
+	payload := []byte("AAPL,213.82,5,2019-01-30 07:00:09.000000")
+	mask := [4]byte{0x11, 0x22, 0x33, 0x44}
+
+	var frame bytes.Buffer
+	frame.WriteByte(0x81) // FIN + text opcode
+	frame.WriteByte(0x80 | byte(len(payload)))
+	frame.Write(mask[:])
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	frame.Write(masked)
+
+	conn := &wsConn{br: bufio.NewReader(&frame)}
+	buf := make([]byte, len(payload))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != string(payload) {
+		t.Fatalf("expected %q; got: %q", payload, buf[:n])
+	}
+}
+
+func TestWsConnReadSkipsPingBeforePayload(t *testing.T) {
+	payload := []byte("tick")
+
+	var frame bytes.Buffer
+	frame.WriteByte(0x89) // FIN + ping opcode
+	frame.WriteByte(0)    // zero-length, unmasked ping
+	frame.WriteByte(0x81) // FIN + text opcode
+	frame.WriteByte(byte(len(payload)))
+	frame.Write(payload)
+
+	conn := &wsConn{br: bufio.NewReader(&frame)}
+	buf := make([]byte, len(payload))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != string(payload) {
+		t.Fatalf("expected %q; got: %q", payload, buf[:n])
+	}
+}
+
+func TestWsConnReadCloseFrameIsEOF(t *testing.T) {
+	var frame bytes.Buffer
+	frame.WriteByte(0x88) // FIN + close opcode
+	frame.WriteByte(0)
+
+	conn := &wsConn{br: bufio.NewReader(&frame)}
+	buf := make([]byte, 16)
+	if _, err := conn.Read(buf); err == nil {
+		t.Fatal("expected a close frame to surface as an error (io.EOF)")
+	}
+}
+
+func TestWsConnReadExtendedLength(t *testing.T) {
+	payload := bytes.Repeat([]byte{'x'}, 200)
+
+	var frame bytes.Buffer
+	frame.WriteByte(0x81)
+	frame.WriteByte(126)
+	frame.Write([]byte{byte(len(payload) >> 8), byte(len(payload))})
+	frame.Write(payload)
+
+	conn := &wsConn{br: bufio.NewReader(&frame)}
+	buf := make([]byte, len(payload))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(payload) {
+		t.Fatalf("expected to read %d bytes; got: %d", len(payload), n)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Order is a single buy/sell instruction emitted by a Strategy in reaction
+// to a candle or to the end of the stream.
+type Order struct {
+	Ticker string
+	Side   string // "buy" or "sell"
+	Price  int
+	Time   time.Time
+}
+
+// Strategy reacts to a stream of candles for a single ticker and produces
+// orders. OnClose is called once the candle stream for that ticker ends and
+// gives the strategy a chance to flatten any open position.
+type Strategy interface {
+	OnCandle(c *Candle) []Order
+	OnClose() []Order
+}
+
+// NewStrategy builds a Strategy by name. Supported names: "sma".
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case "", "sma":
+		return newSMACrossover(10, 30), nil
+	default:
+		return nil, fmt.Errorf("backtest: unknown strategy %q", name)
+	}
+}
+
+// smaCrossover is a simple moving-average crossover strategy: it goes long
+// when the fast average crosses above the slow average and flat when it
+// crosses back below.
+type smaCrossover struct {
+	fastPeriod, slowPeriod int
+	closes                 []int
+	ticker                 string
+	inPosition             bool
+	entry                  int
+}
+
+func newSMACrossover(fast, slow int) *smaCrossover {
+	return &smaCrossover{fastPeriod: fast, slowPeriod: slow}
+}
+
+func (s *smaCrossover) OnCandle(c *Candle) []Order {
+	s.ticker = c.Ticker
+	s.closes = append(s.closes, c.PriceEnd)
+	if len(s.closes) < s.slowPeriod {
+		return nil
+	}
+
+	fast := sma(s.closes, s.fastPeriod)
+	slow := sma(s.closes, s.slowPeriod)
+
+	var orders []Order
+	switch {
+	case fast > slow && !s.inPosition:
+		s.inPosition = true
+		s.entry = c.PriceEnd
+		orders = append(orders, Order{Ticker: c.Ticker, Side: "buy", Price: c.PriceEnd, Time: c.Start})
+	case fast < slow && s.inPosition:
+		s.inPosition = false
+		orders = append(orders, Order{Ticker: c.Ticker, Side: "sell", Price: c.PriceEnd, Time: c.Start})
+	}
+	return orders
+}
+
+func (s *smaCrossover) OnClose() []Order {
+	if !s.inPosition {
+		return nil
+	}
+	s.inPosition = false
+	return []Order{{Ticker: s.ticker, Side: "sell", Price: s.closes[len(s.closes)-1]}}
+}
+
+// sma returns the simple moving average of the last n elements of closes.
+func sma(closes []int, n int) float64 {
+	sum := 0
+	for _, p := range closes[len(closes)-n:] {
+		sum += p
+	}
+	return float64(sum) / float64(n)
+}
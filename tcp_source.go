@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// lineStream is the minimum a transport needs to supply a newline-scannable
+// byte stream: net.Conn satisfies it directly, wsConn decodes WebSocket
+// frames into the same shape.
+type lineStream interface {
+	io.Reader
+	io.Closer
+}
+
+// tcpSource connects to a newline-delimited tick server over TCP,
+// reconnecting with exponential backoff on failure, and buffers decoded
+// lines so a burst from the socket doesn't block on a slow consumer.
+type tcpSource struct {
+	addr    string
+	bufSize int
+}
+
+func (s *tcpSource) Run(ctx context.Context, errch chan<- error, outs ...chan<- *Line) {
+	runBuffered(ctx, s.bufSize, outs, func(ctx context.Context) (lineStream, error) {
+		return net.Dial("tcp", s.addr)
+	})
+}
+
+// wsSource connects to a newline-delimited tick server over WebSocket,
+// with the same reconnect and buffering behavior as tcpSource. Frame
+// payloads are joined into one byte stream with no separator added, so a
+// message that does not end in a newline runs into the next one.
+type wsSource struct {
+	addr    string
+	bufSize int
+}
+
+func (s *wsSource) Run(ctx context.Context, errch chan<- error, outs ...chan<- *Line) {
+	runBuffered(ctx, s.bufSize, outs, func(ctx context.Context) (lineStream, error) {
+		return dialWebsocket(s.addr)
+	})
+}
+
+// runBuffered is the reconnect-with-backoff driver shared by tcpSource and
+// wsSource: it repeatedly dials, scans decoded ticks into a bounded
+// channel, and fans them out to outs until ctx is canceled. Dial
+// failures, read errors and malformed ticks are transient by nature of a
+// live feed, so they're logged and retried here rather than sent to the
+// pipeline's fatal errch — a streaming run is meant to keep going
+// indefinitely, and only gives up when ctx itself is canceled.
+func runBuffered(ctx context.Context, bufSize int, outs []chan<- *Line, dial func(context.Context) (lineStream, error)) {
+	defer closeLines(outs)
+
+	buf := make(chan *Line, bufSize)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(buf)
+		defer close(done)
+
+		backoff := time.Second
+		for ctx.Err() == nil {
+			conn, err := dial(ctx)
+			if err != nil {
+				log.Printf("stream: dial failed, retrying in %s: %v", backoff, err)
+				if !sleepCtx(ctx, backoff) {
+					return
+				}
+				backoff = nextBackoff(backoff)
+				continue
+			}
+
+			backoff = time.Second
+			readLines(ctx, conn, buf)
+			conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case line, ok := <-buf:
+			if !ok {
+				<-done
+				return
+			}
+			for _, out := range outs {
+				out <- line
+			}
+		case <-ctx.Done():
+			<-done
+			return
+		}
+	}
+}
+
+// readLines scans newline-delimited ticks off conn into buf until the
+// connection closes, a read error occurs, or ctx is canceled. Both a
+// read error and a malformed tick are logged and skipped/reconnected,
+// never treated as fatal to the run.
+func readLines(ctx context.Context, conn lineStream, buf chan<- *Line) {
+	// Close conn on cancellation so a Scan blocked on an idle connection
+	// returns instead of waiting for the next tick.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		line, err := parseTick(scanner.Text())
+		if err != nil {
+			log.Printf("stream: dropping malformed tick: %v", err)
+			continue
+		}
+		if line == nil {
+			continue
+		}
+
+		select {
+		case buf <- line:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		log.Printf("stream: connection read error, reconnecting: %v", err)
+	}
+}
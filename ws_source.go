@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// wsConn adapts a single client-side WebSocket connection (RFC 6455) to
+// io.Reader, decoding incoming frames into a flat byte stream so it can be
+// scanned the same way as a raw TCP tick feed.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	rest []byte
+}
+
+// dialWebsocket dials addr (ws:// or wss://... note: TLS is not
+// supported, only ws://) and performs the RFC 6455 opening handshake.
+func dialWebsocket(addr string) (*wsConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	if !strings.Contains(host, ":") {
+		host += ":80"
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	path := u.RequestURI()
+	if path == "" {
+		path = "/"
+	}
+
+	req := fmt.Sprintf(
+		"GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		path, u.Host, base64.StdEncoding.EncodeToString(key))
+	if _, err := conn.Write([]byte(req)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	status, err := br.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.Contains(status, "101") {
+		conn.Close()
+		return nil, fmt.Errorf("ws: handshake rejected: %s", strings.TrimSpace(status))
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+func (w *wsConn) Close() error { return w.conn.Close() }
+
+// Read returns decoded frame payloads as a flat byte stream. Ping/pong
+// frames are consumed transparently; a close frame surfaces as io.EOF.
+func (w *wsConn) Read(p []byte) (int, error) {
+	for len(w.rest) == 0 {
+		payload, opcode, err := w.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch opcode {
+		case 0x8: // close
+			return 0, io.EOF
+		case 0x9, 0xA: // ping, pong
+			continue
+		default:
+			w.rest = payload
+		}
+	}
+
+	n := copy(p, w.rest)
+	w.rest = w.rest[n:]
+	return n, nil
+}
+
+func (w *wsConn) readFrame() ([]byte, byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(w.br, head); err != nil {
+		return nil, 0, err
+	}
+
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(w.br, ext); err != nil {
+			return nil, 0, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(w.br, ext); err != nil {
+			return nil, 0, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(w.br, maskKey[:]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(w.br, payload); err != nil {
+		return nil, 0, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+
+	return payload, opcode, nil
+}